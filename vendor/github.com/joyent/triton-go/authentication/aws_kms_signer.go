@@ -0,0 +1,101 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/hashicorp/errwrap"
+	"golang.org/x/crypto/ssh"
+)
+
+// AWSKMSSignerInput describes the KMS key backing an AWSKMSSigner.
+type AWSKMSSignerInput struct {
+	KMSKeyID string
+	Client   kmsiface.KMSAPI
+}
+
+// AWSKMSSigner implements crypto.Signer on top of an asymmetric AWS KMS key,
+// so the private key never leaves the HSM backing the KMS key. It's meant to
+// be passed to NewCryptoSigner.
+type AWSKMSSigner struct {
+	client   kmsiface.KMSAPI
+	kmsKeyID string
+	public   crypto.PublicKey
+}
+
+// NewAWSKMSSigner fetches the public key for kmsKeyID and returns a
+// crypto.Signer that signs through KMS.
+func NewAWSKMSSigner(input AWSKMSSignerInput) (*AWSKMSSigner, error) {
+	out, err := input.Client.GetPublicKey(&kms.GetPublicKeyInput{
+		KeyId: aws.String(input.KMSKeyID),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error fetching public key from KMS: {{err}}", err)
+	}
+
+	public, err := x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error parsing KMS public key: {{err}}", err)
+	}
+
+	return &AWSKMSSigner{
+		client:   input.Client,
+		kmsKeyID: input.KMSKeyID,
+		public:   public,
+	}, nil
+}
+
+func (s *AWSKMSSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign implements crypto.Signer by asking KMS to sign digest as a
+// precomputed digest. Only RSA keys (RSASSA-PKCS1-V1_5-SHA-256) and ECDSA
+// keys (ECDSA-SHA-256) are supported; any other key type returns an error.
+// opts.HashFunc must be crypto.SHA256 or the zero crypto.Hash, and digest is
+// passed to KMS unchanged in both cases; any other hash returns an error.
+func (s *AWSKMSSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var algorithm string
+	switch s.public.(type) {
+	case *rsa.PublicKey:
+		algorithm = kms.SigningAlgorithmSpecRsassaPkcs1V15Sha256
+	case *ecdsa.PublicKey:
+		algorithm = kms.SigningAlgorithmSpecEcdsaSha256
+	default:
+		return nil, fmt.Errorf("Unsupported KMS public key type: %T", s.public)
+	}
+
+	if opts.HashFunc() != crypto.SHA256 && opts.HashFunc() != crypto.Hash(0) {
+		return nil, fmt.Errorf("Unsupported hash function for KMS signing: %s", opts.HashFunc())
+	}
+
+	out, err := s.client.Sign(&kms.SignInput{
+		KeyId:            aws.String(s.kmsKeyID),
+		Message:          digest,
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(algorithm),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("Error signing with KMS: {{err}}", err)
+	}
+
+	return out.Signature, nil
+}
+
+// KeyFingerprint derives the SSH-style key fingerprint for the KMS public
+// key, so it can be registered with Triton/Manta the same way any other key
+// is.
+func (s *AWSKMSSigner) KeyFingerprint() (string, error) {
+	publicKey, err := ssh.NewPublicKey(s.public)
+	if err != nil {
+		return "", errwrap.Wrapf("Error converting KMS public key to ssh.PublicKey: {{err}}", err)
+	}
+	return formatPublicKeyFingerprint(publicKey, true), nil
+}
@@ -0,0 +1,136 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeKMSClient implements just the two kmsiface.KMSAPI methods
+// NewAWSKMSSigner/AWSKMSSigner.Sign use; every other method panics via the
+// embedded nil interface if exercised, which none of these tests do. Sign
+// produces a real ASN.1 DER signature with privateKey, the way KMS would,
+// so callers can verify the signature that eventually reaches the wire.
+type fakeKMSClient struct {
+	kmsiface.KMSAPI
+
+	publicKeyDER []byte
+	privateKey   *ecdsa.PrivateKey
+	lastInput    *kms.SignInput
+}
+
+func (f *fakeKMSClient) GetPublicKey(in *kms.GetPublicKeyInput) (*kms.GetPublicKeyOutput, error) {
+	return &kms.GetPublicKeyOutput{PublicKey: f.publicKeyDER}, nil
+}
+
+func (f *fakeKMSClient) Sign(in *kms.SignInput) (*kms.SignOutput, error) {
+	f.lastInput = in
+	sig, err := ecdsa.SignASN1(rand.Reader, f.privateKey, in.Message)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.SignOutput{Signature: sig}, nil
+}
+
+func TestAWSKMSSignerSignUsesECDSAAlgorithm(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeKMSClient{publicKeyDER: der, privateKey: key}
+
+	signer, err := NewAWSKMSSigner(AWSKMSSignerInput{
+		KMSKeyID: "arn:aws:kms:us-east-1:000000000000:key/test",
+		Client:   client,
+	})
+	if err != nil {
+		t.Fatalf("NewAWSKMSSigner() returned error: %s", err)
+	}
+
+	digest := sha256.Sum256([]byte("data to sign"))
+	der2, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Sign() returned error: %s", err)
+	}
+	if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], der2) {
+		t.Fatal("Sign() returned a signature that doesn't verify against the public key")
+	}
+	if aws.StringValue(client.lastInput.SigningAlgorithm) != kms.SigningAlgorithmSpecEcdsaSha256 {
+		t.Fatalf("SigningAlgorithm = %q, want %q", aws.StringValue(client.lastInput.SigningAlgorithm), kms.SigningAlgorithmSpecEcdsaSha256)
+	}
+
+	fingerprint, err := signer.KeyFingerprint()
+	if err != nil {
+		t.Fatalf("KeyFingerprint() returned error: %s", err)
+	}
+	if fingerprint == "" {
+		t.Fatal("KeyFingerprint() returned an empty fingerprint")
+	}
+}
+
+// TestAWSKMSSignerThroughCryptoSignerIsVerifiable exercises the full path -
+// KMS's ASN.1 DER signature re-encoded by CryptoSigner into the SSH wire
+// format - end to end, since that re-encode is where a prior regression
+// slipped through.
+func TestAWSKMSSignerThroughCryptoSignerIsVerifiable(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeKMSClient{publicKeyDER: der, privateKey: key}
+
+	kmsSigner, err := NewAWSKMSSigner(AWSKMSSignerInput{
+		KMSKeyID: "arn:aws:kms:us-east-1:000000000000:key/test",
+		Client:   client,
+	})
+	if err != nil {
+		t.Fatalf("NewAWSKMSSigner() returned error: %s", err)
+	}
+
+	signer, err := NewCryptoSigner(CryptoSignerInput{
+		AccountName: "test-account",
+		Signer:      kmsSigner,
+		PublicKey:   sshPub,
+	})
+	if err != nil {
+		t.Fatalf("NewCryptoSigner() returned error: %s", err)
+	}
+
+	const toSign = "verify-me"
+	sigStr, _, err := signer.SignRaw(toSign)
+	if err != nil {
+		t.Fatalf("SignRaw() returned error: %s", err)
+	}
+	blob, err := base64.StdEncoding.DecodeString(sigStr)
+	if err != nil {
+		t.Fatalf("signature is not valid base64: %s", err)
+	}
+
+	if err := sshPub.Verify([]byte(toSign), &ssh.Signature{Format: sshPub.Type(), Blob: blob}); err != nil {
+		t.Fatalf("signature failed to verify against the public key: %s", err)
+	}
+}
@@ -0,0 +1,139 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/errwrap"
+	"golang.org/x/crypto/ssh"
+)
+
+// CryptoSignerInput adapts an arbitrary crypto.Signer - a YubiKey, a
+// PKCS#11 token, gpg-agent, a cloud KMS key, anything - into a Triton/Manta
+// Signer. PublicKey must be the ssh.PublicKey corresponding to
+// Signer.Public(); implementations that can't cheaply produce an
+// ssh.PublicKey themselves can build one with ssh.NewPublicKey.
+type CryptoSignerInput struct {
+	AccountName string
+	Signer      crypto.Signer
+	PublicKey   ssh.PublicKey
+}
+
+// CryptoSigner implements Signer on top of any crypto.Signer, letting the
+// private key live outside the process entirely (HSM, KMS, smart card) as
+// long as something can hand back signatures for a digest.
+type CryptoSigner struct {
+	formattedKeyFingerprint string
+	algorithm               string
+	accountName             string
+	keyIdentifier           string
+
+	signer crypto.Signer
+}
+
+// NewCryptoSigner wraps input.Signer so it can sign Triton/Manta requests.
+func NewCryptoSigner(input CryptoSignerInput) (*CryptoSigner, error) {
+	if input.Signer == nil {
+		return nil, fmt.Errorf("CryptoSignerInput.Signer must not be nil")
+	}
+	if input.PublicKey == nil {
+		return nil, fmt.Errorf("CryptoSignerInput.PublicKey must not be nil")
+	}
+
+	signer := &CryptoSigner{
+		accountName: input.AccountName,
+		signer:      input.Signer,
+	}
+	signer.formattedKeyFingerprint = formatPublicKeyFingerprint(input.PublicKey, true)
+	signer.keyIdentifier = fmt.Sprintf("/%s/keys/%s", signer.accountName, signer.formattedKeyFingerprint)
+
+	_, algorithm, err := signer.SignRaw("HelloWorld")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot sign using the provided crypto.Signer: %s", err)
+	}
+	signer.algorithm = algorithm
+
+	return signer, nil
+}
+
+func (s *CryptoSigner) sign(data []byte) (httpAuthSignature, error) {
+	switch pub := s.signer.Public().(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		blob, err := s.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error signing data: {{err}}", err)
+		}
+		return newRSASignatureWithAlgorithm(blob, "rsa-sha256")
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		der, err := s.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error signing data: {{err}}", err)
+		}
+		blob, err := asn1ECDSASignatureToSSHWire(der)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error re-encoding ECDSA signature: {{err}}", err)
+		}
+		return newECDSASignature(blob)
+	case ed25519.PublicKey:
+		blob, err := s.signer.Sign(rand.Reader, data, crypto.Hash(0))
+		if err != nil {
+			return nil, errwrap.Wrapf("Error signing data: {{err}}", err)
+		}
+		return newED25519Signature(blob)
+	default:
+		return nil, fmt.Errorf("Unsupported public key type: %T", pub)
+	}
+}
+
+func (s *CryptoSigner) Sign(dateHeader string) (string, error) {
+	const headerName = "date"
+
+	authSignature, err := s.sign([]byte(fmt.Sprintf("%s: %s", headerName, dateHeader)))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(authorizationHeaderFormat, s.keyIdentifier,
+		authSignature.SignatureType(), authSignature.String()), nil
+}
+
+func (s *CryptoSigner) SignRaw(toSign string) (string, string, error) {
+	authSignature, err := s.sign([]byte(toSign))
+	if err != nil {
+		return "", "", err
+	}
+
+	return authSignature.String(), authSignature.SignatureType(), nil
+}
+
+// asn1ECDSASignatureToSSHWire re-encodes the ASN.1 DER (r,s) signature that
+// crypto.Signer.Sign returns for an ECDSA key into the SSH-wire mpint-pair
+// blob SSHAgentSigner/PrivateKeySigner produce (golang.org/x/crypto/ssh
+// does this same re-encode internally in wrappedSigner.Sign), so every
+// ECDSA-backed Signer implementation emits the same wire format.
+func asn1ECDSASignatureToSSHWire(der []byte) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	return ssh.Marshal(sig), nil
+}
+
+func (s *CryptoSigner) KeyFingerprint() string {
+	return s.formattedKeyFingerprint
+}
+
+func (s *CryptoSigner) DefaultAlgorithm() string {
+	return s.algorithm
+}
@@ -0,0 +1,140 @@
+package authentication
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewCryptoSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaPub, err := ssh.NewPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("rsa", func(t *testing.T) {
+		signer, err := NewCryptoSigner(CryptoSignerInput{
+			AccountName: "test-account",
+			Signer:      rsaKey,
+			PublicKey:   rsaPub,
+		})
+		if err != nil {
+			t.Fatalf("NewCryptoSigner() returned error: %s", err)
+		}
+		if got := signer.DefaultAlgorithm(); got != "rsa-sha256" {
+			t.Fatalf("DefaultAlgorithm() = %q, want %q", got, "rsa-sha256")
+		}
+	})
+
+	t.Run("ecdsa", func(t *testing.T) {
+		signer, err := NewCryptoSigner(CryptoSignerInput{
+			AccountName: "test-account",
+			Signer:      ecdsaKey,
+			PublicKey:   ecdsaPub,
+		})
+		if err != nil {
+			t.Fatalf("NewCryptoSigner() returned error: %s", err)
+		}
+		if got := signer.DefaultAlgorithm(); got != "ecdsa-sha256" {
+			t.Fatalf("DefaultAlgorithm() = %q, want %q", got, "ecdsa-sha256")
+		}
+	})
+
+	t.Run("requires a signer", func(t *testing.T) {
+		_, err := NewCryptoSigner(CryptoSignerInput{AccountName: "test-account", PublicKey: rsaPub})
+		if err == nil {
+			t.Fatal("NewCryptoSigner() with a nil Signer should have returned an error")
+		}
+	})
+
+	t.Run("requires a public key", func(t *testing.T) {
+		_, err := NewCryptoSigner(CryptoSignerInput{AccountName: "test-account", Signer: rsaKey})
+		if err == nil {
+			t.Fatal("NewCryptoSigner() with a nil PublicKey should have returned an error")
+		}
+	})
+}
+
+// TestCryptoSignerProducesVerifiableSignatures checks the signature blob
+// CryptoSigner hands back actually verifies against the public key in the
+// SSH wire format other Signer implementations use - not just that some
+// bytes came back.
+func TestCryptoSignerProducesVerifiableSignatures(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaPub, err := ssh.NewPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const toSign = "verify-me"
+
+	t.Run("rsa", func(t *testing.T) {
+		signer, err := NewCryptoSigner(CryptoSignerInput{AccountName: "test-account", Signer: rsaKey, PublicKey: rsaPub})
+		if err != nil {
+			t.Fatalf("NewCryptoSigner() returned error: %s", err)
+		}
+
+		sigStr, _, err := signer.SignRaw(toSign)
+		if err != nil {
+			t.Fatalf("SignRaw() returned error: %s", err)
+		}
+		blob, err := base64.StdEncoding.DecodeString(sigStr)
+		if err != nil {
+			t.Fatalf("signature is not valid base64: %s", err)
+		}
+
+		if err := rsaPub.Verify([]byte(toSign), &ssh.Signature{Format: "rsa-sha2-256", Blob: blob}); err != nil {
+			t.Fatalf("signature failed to verify against the public key: %s", err)
+		}
+	})
+
+	t.Run("ecdsa", func(t *testing.T) {
+		signer, err := NewCryptoSigner(CryptoSignerInput{AccountName: "test-account", Signer: ecdsaKey, PublicKey: ecdsaPub})
+		if err != nil {
+			t.Fatalf("NewCryptoSigner() returned error: %s", err)
+		}
+
+		sigStr, _, err := signer.SignRaw(toSign)
+		if err != nil {
+			t.Fatalf("SignRaw() returned error: %s", err)
+		}
+		blob, err := base64.StdEncoding.DecodeString(sigStr)
+		if err != nil {
+			t.Fatalf("signature is not valid base64: %s", err)
+		}
+
+		if err := ecdsaPub.Verify([]byte(toSign), &ssh.Signature{Format: ecdsaPub.Type(), Blob: blob}); err != nil {
+			t.Fatalf("signature failed to verify against the public key: %s", err)
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package authentication
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// formatPublicKeyFingerprint renders the fingerprint of key the way the
+// CloudAPI/Manta key APIs expect it: an md5 fingerprint for legacy callers,
+// or a prefixed SHA256 fingerprint when useSHA256 is true.
+func formatPublicKeyFingerprint(key ssh.PublicKey, useSHA256 bool) string {
+	if useSHA256 {
+		sum := sha256.Sum256(key.Marshal())
+		return "SHA256:" + strings.TrimRight(base64.StdEncoding.EncodeToString(sum[:]), "=")
+	}
+
+	sum := md5.Sum(key.Marshal())
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
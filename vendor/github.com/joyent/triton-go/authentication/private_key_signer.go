@@ -0,0 +1,222 @@
+package authentication
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	"golang.org/x/crypto/ssh"
+)
+
+// PrivateKeySignerInput describes a PEM-encoded private key to be used to
+// sign Triton/Manta requests. PassphrasePrompt is only consulted by
+// NewPrivateKeySignerWithPassphrase, and only when the key turns out to be
+// encrypted and no passphrase was supplied up front.
+type PrivateKeySignerInput struct {
+	KeyID              string
+	PrivateKeyMaterial []byte
+	AccountName        string
+	PassphrasePrompt   func() ([]byte, error)
+}
+
+type PrivateKeySigner struct {
+	formattedKeyFingerprint string
+	keyFingerprint          string
+	algorithm               string
+	accountName             string
+	keyIdentifier           string
+
+	key interface{}
+}
+
+// NewPrivateKeySigner returns a PrivateKeySigner that signs requests with an
+// unencrypted PKCS#1 RSA private key.
+func NewPrivateKeySigner(input PrivateKeySignerInput) (*PrivateKeySigner, error) {
+	block, _ := pem.Decode(input.PrivateKeyMaterial)
+	if block == nil {
+		return nil, fmt.Errorf("Unable to decode PEM block containing private key")
+	}
+
+	rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error parsing private key: {{err}}", err)
+	}
+
+	return newPrivateKeySigner(input, rsaKey)
+}
+
+// NewPrivateKeySignerWithPassphrase returns a PrivateKeySigner backed by a
+// PEM-encoded RSA, ECDSA, or Ed25519 private key. It handles OpenSSH-format
+// keys with or without a passphrase, unencrypted PKCS#1/SEC1/PKCS#8 blocks,
+// and PEM blocks encrypted with a DEK-Info header; PKCS#8 "ENCRYPTED PRIVATE
+// KEY" blocks are not decrypted and return a parse error. If the key is
+// encrypted and passphrase is empty, input.PassphrasePrompt is invoked to
+// obtain one; callers that don't set PassphrasePrompt get the error from
+// ssh.ParseRawPrivateKeyWithPassphrase back, wrapped, instead of a prompt.
+// Unencrypted keys are parsed with ssh.ParseRawPrivateKey
+// directly: ssh.ParseRawPrivateKeyWithPassphrase errors on any PEM block it
+// can't positively identify as encrypted, so it isn't a safe "maybe
+// encrypted" catch-all.
+func NewPrivateKeySignerWithPassphrase(input PrivateKeySignerInput, passphrase []byte) (*PrivateKeySigner, error) {
+	block, _ := pem.Decode(input.PrivateKeyMaterial)
+	if block == nil {
+		return nil, fmt.Errorf("Unable to decode PEM block containing private key")
+	}
+
+	var key interface{}
+	var err error
+
+	if x509.IsEncryptedPEMBlock(block) || isEncryptedOpenSSHKey(block) { //nolint:staticcheck
+		if len(passphrase) == 0 && input.PassphrasePrompt != nil {
+			prompted, promptErr := input.PassphrasePrompt()
+			if promptErr != nil {
+				return nil, errwrap.Wrapf("Error prompting for passphrase: {{err}}", promptErr)
+			}
+			passphrase = prompted
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(input.PrivateKeyMaterial, passphrase)
+	} else {
+		key, err = ssh.ParseRawPrivateKey(input.PrivateKeyMaterial)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error parsing private key: {{err}}", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return newPrivateKeySigner(input, k)
+	case *ed25519.PrivateKey:
+		return newPrivateKeySigner(input, *k)
+	default:
+		return nil, fmt.Errorf("Unsupported private key type: %T", key)
+	}
+}
+
+// isEncryptedOpenSSHKey reports whether block looks like an
+// OpenSSH-format private key (RFC 4716-ish "OPENSSH PRIVATE KEY" PEM type)
+// protected by a passphrase. Unlike PKCS#1/PKCS#8 blocks, OpenSSH-format
+// keys don't set block.Headers, so encryption has to be detected by
+// attempting an unauthenticated parse.
+func isEncryptedOpenSSHKey(block *pem.Block) bool {
+	if block.Type != "OPENSSH PRIVATE KEY" {
+		return false
+	}
+	_, err := ssh.ParseRawPrivateKey(pem.EncodeToMemory(block))
+	return err != nil
+}
+
+func newPrivateKeySigner(input PrivateKeySignerInput, key interface{}) (*PrivateKeySigner, error) {
+	signer := &PrivateKeySigner{
+		keyFingerprint: input.KeyID,
+		accountName:    input.AccountName,
+		key:            key,
+	}
+
+	publicKey, err := publicKeyFromPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	signer.formattedKeyFingerprint = formatPublicKeyFingerprint(publicKey, true)
+	signer.keyIdentifier = fmt.Sprintf("/%s/keys/%s", signer.accountName, signer.formattedKeyFingerprint)
+
+	_, algorithm, err := signer.SignRaw("HelloWorld")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot sign using the provided private key: %s", err)
+	}
+	signer.algorithm = algorithm
+
+	return signer, nil
+}
+
+func publicKeyFromPrivateKey(key interface{}) (ssh.PublicKey, error) {
+	var signer ssh.Signer
+	var err error
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signer, err = ssh.NewSignerFromKey(k)
+	case *ecdsa.PrivateKey:
+		signer, err = ssh.NewSignerFromKey(k)
+	case ed25519.PrivateKey:
+		signer, err = ssh.NewSignerFromKey(k)
+	default:
+		return nil, fmt.Errorf("Unsupported private key type: %T", key)
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("Error deriving public key: {{err}}", err)
+	}
+
+	return signer.PublicKey(), nil
+}
+
+func (s *PrivateKeySigner) sign(data []byte) (httpAuthSignature, error) {
+	switch k := s.key.(type) {
+	case *rsa.PrivateKey:
+		signer, err := ssh.NewSignerFromKey(k)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error creating signer: {{err}}", err)
+		}
+		signature, err := signer.Sign(nil, data)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error signing data: {{err}}", err)
+		}
+		return newRSASignature(signature.Blob)
+	case *ecdsa.PrivateKey:
+		signer, err := ssh.NewSignerFromKey(k)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error creating signer: {{err}}", err)
+		}
+		signature, err := signer.Sign(rand.Reader, data)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error signing data: {{err}}", err)
+		}
+		return newECDSASignature(signature.Blob)
+	case ed25519.PrivateKey:
+		signer, err := ssh.NewSignerFromKey(k)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error creating signer: {{err}}", err)
+		}
+		signature, err := signer.Sign(nil, data)
+		if err != nil {
+			return nil, errwrap.Wrapf("Error signing data: {{err}}", err)
+		}
+		return newED25519Signature(signature.Blob)
+	default:
+		return nil, fmt.Errorf("Unsupported private key type: %T", s.key)
+	}
+}
+
+func (s *PrivateKeySigner) Sign(dateHeader string) (string, error) {
+	const headerName = "date"
+
+	authSignature, err := s.sign([]byte(fmt.Sprintf("%s: %s", headerName, dateHeader)))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(authorizationHeaderFormat, s.keyIdentifier,
+		authSignature.SignatureType(), authSignature.String()), nil
+}
+
+func (s *PrivateKeySigner) SignRaw(toSign string) (string, string, error) {
+	authSignature, err := s.sign([]byte(toSign))
+	if err != nil {
+		return "", "", err
+	}
+
+	return authSignature.String(), authSignature.SignatureType(), nil
+}
+
+func (s *PrivateKeySigner) KeyFingerprint() string {
+	return s.formattedKeyFingerprint
+}
+
+func (s *PrivateKeySigner) DefaultAlgorithm() string {
+	return s.algorithm
+}
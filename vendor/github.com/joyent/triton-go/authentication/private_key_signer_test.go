@@ -0,0 +1,149 @@
+package authentication
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewPrivateKeySignerWithPassphrasePKCS8Ed25519(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// x509.MarshalPKCS8PrivateKey is what openssl genpkey -algorithm ed25519
+	// produces too: an unencrypted "PRIVATE KEY" block whose parsed value is
+	// an ed25519.PrivateKey value, not a pointer.
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	signer, err := NewPrivateKeySignerWithPassphrase(PrivateKeySignerInput{
+		KeyID:              "test-key",
+		AccountName:        "test-account",
+		PrivateKeyMaterial: pemBytes,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewPrivateKeySignerWithPassphrase() returned error: %s", err)
+	}
+
+	if got := signer.DefaultAlgorithm(); got != "ed25519" {
+		t.Fatalf("DefaultAlgorithm() = %q, want %q", got, "ed25519")
+	}
+}
+
+func TestNewPrivateKeySignerWithPassphraseUnencryptedPKCS1RSA(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	signer, err := NewPrivateKeySignerWithPassphrase(PrivateKeySignerInput{
+		KeyID:              "test-key",
+		AccountName:        "test-account",
+		PrivateKeyMaterial: pemBytes,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewPrivateKeySignerWithPassphrase() returned error: %s", err)
+	}
+
+	if got := signer.DefaultAlgorithm(); got != "rsa-sha1" {
+		t.Fatalf("DefaultAlgorithm() = %q, want %q", got, "rsa-sha1")
+	}
+}
+
+func TestNewPrivateKeySignerWithPassphraseEncryptedOpenSSHKey(t *testing.T) {
+	const passphrase = "correct-horse-battery-staple"
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("passphrase supplied directly", func(t *testing.T) {
+		signer, err := NewPrivateKeySignerWithPassphrase(PrivateKeySignerInput{
+			KeyID:              "test-key",
+			AccountName:        "test-account",
+			PrivateKeyMaterial: pem.EncodeToMemory(pemBytes),
+		}, []byte(passphrase))
+		if err != nil {
+			t.Fatalf("NewPrivateKeySignerWithPassphrase() returned error: %s", err)
+		}
+		if got := signer.DefaultAlgorithm(); got != "ed25519" {
+			t.Fatalf("DefaultAlgorithm() = %q, want %q", got, "ed25519")
+		}
+	})
+
+	t.Run("passphrase from prompt", func(t *testing.T) {
+		promptCalled := false
+		signer, err := NewPrivateKeySignerWithPassphrase(PrivateKeySignerInput{
+			KeyID:              "test-key",
+			AccountName:        "test-account",
+			PrivateKeyMaterial: pem.EncodeToMemory(pemBytes),
+			PassphrasePrompt: func() ([]byte, error) {
+				promptCalled = true
+				return []byte(passphrase), nil
+			},
+		}, nil)
+		if err != nil {
+			t.Fatalf("NewPrivateKeySignerWithPassphrase() returned error: %s", err)
+		}
+		if !promptCalled {
+			t.Fatal("PassphrasePrompt was never invoked for an encrypted key with no passphrase")
+		}
+		if got := signer.DefaultAlgorithm(); got != "ed25519" {
+			t.Fatalf("DefaultAlgorithm() = %q, want %q", got, "ed25519")
+		}
+	})
+
+	t.Run("wrong passphrase is rejected", func(t *testing.T) {
+		_, err := NewPrivateKeySignerWithPassphrase(PrivateKeySignerInput{
+			KeyID:              "test-key",
+			AccountName:        "test-account",
+			PrivateKeyMaterial: pem.EncodeToMemory(pemBytes),
+		}, []byte("wrong-passphrase"))
+		if err == nil {
+			t.Fatal("NewPrivateKeySignerWithPassphrase() should have rejected the wrong passphrase")
+		}
+	})
+}
+
+func TestNewPrivateKeySignerWithPassphrasePromptErrorPropagates(t *testing.T) {
+	const passphrase = "correct-horse-battery-staple"
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewPrivateKeySignerWithPassphrase(PrivateKeySignerInput{
+		KeyID:              "test-key",
+		AccountName:        "test-account",
+		PrivateKeyMaterial: pem.EncodeToMemory(pemBytes),
+		PassphrasePrompt: func() ([]byte, error) {
+			return nil, errors.New("user cancelled")
+		},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "user cancelled") {
+		t.Fatalf("error = %v, want an error mentioning %q", err, "user cancelled")
+	}
+}
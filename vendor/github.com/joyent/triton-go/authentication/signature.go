@@ -0,0 +1,101 @@
+package authentication
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const authorizationHeaderFormat = `Signature keyId="%s",algorithm="%s",headers="date",signature="%s"`
+
+// httpAuthSignature is satisfied by every key-type-specific signature wrapper
+// so that SSHAgentSigner and PrivateKeySigner can build the Authorization
+// header without caring how the bytes underneath were produced.
+type httpAuthSignature interface {
+	String() string
+	SignatureType() string
+}
+
+type rsaSignature struct {
+	signature string
+	algorithm string
+}
+
+// newRSASignature wraps a raw ssh-rsa (SHA-1) signature blob.
+func newRSASignature(blob []byte) (httpAuthSignature, error) {
+	return newRSASignatureWithAlgorithm(blob, "rsa-sha1")
+}
+
+// newRSASignatureWithAlgorithm wraps a raw RSA signature blob produced under
+// a specific digest, e.g. "rsa-sha256" or "rsa-sha512".
+func newRSASignatureWithAlgorithm(blob []byte, algorithm string) (httpAuthSignature, error) {
+	return &rsaSignature{
+		signature: base64.StdEncoding.EncodeToString(blob),
+		algorithm: algorithm,
+	}, nil
+}
+
+func (s *rsaSignature) String() string {
+	return s.signature
+}
+
+func (s *rsaSignature) SignatureType() string {
+	return s.algorithm
+}
+
+type ecdsaSignature struct {
+	signature string
+}
+
+func newECDSASignature(blob []byte) (httpAuthSignature, error) {
+	return &ecdsaSignature{
+		signature: base64.StdEncoding.EncodeToString(blob),
+	}, nil
+}
+
+func (s *ecdsaSignature) String() string {
+	return s.signature
+}
+
+func (s *ecdsaSignature) SignatureType() string {
+	return "ecdsa-sha256"
+}
+
+type ed25519Signature struct {
+	signature string
+}
+
+func newED25519Signature(blob []byte) (httpAuthSignature, error) {
+	return &ed25519Signature{
+		signature: base64.StdEncoding.EncodeToString(blob),
+	}, nil
+}
+
+func (s *ed25519Signature) String() string {
+	return s.signature
+}
+
+func (s *ed25519Signature) SignatureType() string {
+	return "ed25519"
+}
+
+// keyFormatToKeyType maps the wire format reported on an ssh.Signature (or an
+// ssh.PublicKey) to the short key type this package uses to pick the right
+// httpAuthSignature implementation.
+func keyFormatToKeyType(format string) (string, error) {
+	switch format {
+	case ssh.KeyAlgoRSA:
+		return "rsa", nil
+	case "rsa-sha2-256":
+		return "rsa-sha256", nil
+	case "rsa-sha2-512":
+		return "rsa-sha512", nil
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, ssh.KeyAlgoSKECDSA256:
+		return "ecdsa", nil
+	case ssh.KeyAlgoED25519, ssh.KeyAlgoSKED25519:
+		return "ed25519", nil
+	default:
+		return "", fmt.Errorf("Unsupported algorithm from SSH agent: %s", format)
+	}
+}
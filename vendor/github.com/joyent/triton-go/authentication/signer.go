@@ -0,0 +1,12 @@
+package authentication
+
+// Signer is implemented by anything that can produce a Triton/Manta HTTP
+// Signature Auth header. SSHAgentSigner and PrivateKeySigner satisfy it
+// directly; CryptoSigner adapts any crypto.Signer (including KMS-backed
+// ones) to it.
+type Signer interface {
+	Sign(dateHeader string) (string, error)
+	SignRaw(toSign string) (string, string, error)
+	KeyFingerprint() string
+	DefaultAlgorithm() string
+}
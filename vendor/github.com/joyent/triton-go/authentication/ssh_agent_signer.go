@@ -26,8 +26,9 @@ type SSHAgentSigner struct {
 	accountName             string
 	keyIdentifier           string
 
-	agent agent.Agent
-	key   ssh.PublicKey
+	agent    agent.Agent
+	extAgent agent.ExtendedAgent
+	key      ssh.PublicKey
 }
 
 func NewSSHAgentSigner(keyFingerprint, accountName string) (*SSHAgentSigner, error) {
@@ -49,6 +50,14 @@ func NewSSHAgentSigner(keyFingerprint, accountName string) (*SSHAgentSigner, err
 		agent:          ag,
 	}
 
+	// Agents that implement agent.ExtendedAgent (effectively every OpenSSH
+	// agent in common use today) accept the flag bits from section 4.5.1 of
+	// the ssh-agent protocol draft, letting us ask for RSA-SHA2 signatures
+	// instead of the legacy SHA-1 "ssh-rsa" signature.
+	if extAg, ok := ag.(agent.ExtendedAgent); ok {
+		signer.extAgent = extAg
+	}
+
 	matchingKey, err := signer.MatchKey()
 	if err != nil {
 		return nil, err
@@ -101,64 +110,75 @@ func (s *SSHAgentSigner) MatchKey() (ssh.PublicKey, error) {
 func (s *SSHAgentSigner) Sign(dateHeader string) (string, error) {
 	const headerName = "date"
 
-	signature, err := s.agent.Sign(s.key, []byte(fmt.Sprintf("%s: %s", headerName, dateHeader)))
+	authSignature, err := s.signWithAgent([]byte(fmt.Sprintf("%s: %s", headerName, dateHeader)))
 	if err != nil {
-		return "", errwrap.Wrapf("Error signing date header: {{err}}", err)
+		return "", err
 	}
 
-	keyFormat, err := keyFormatToKeyType(signature.Format)
-	if err != nil {
-		return "", errwrap.Wrapf("Error reading signature: {{err}}", err)
-	}
+	return fmt.Sprintf(authorizationHeaderFormat, s.keyIdentifier,
+		authSignature.SignatureType(), authSignature.String()), nil
+}
 
-	var authSignature httpAuthSignature
-	switch keyFormat {
-	case "rsa":
-		authSignature, err = newRSASignature(signature.Blob)
-		if err != nil {
-			return "", errwrap.Wrapf("Error reading signature: {{err}}", err)
-		}
-	case "ecdsa":
-		authSignature, err = newECDSASignature(signature.Blob)
-		if err != nil {
-			return "", errwrap.Wrapf("Error reading signature: {{err}}", err)
-		}
-	default:
-		return "", fmt.Errorf("Unsupported algorithm from SSH agent: %s", signature.Format)
+func (s *SSHAgentSigner) SignRaw(toSign string) (string, string, error) {
+	authSignature, err := s.signWithAgent([]byte(toSign))
+	if err != nil {
+		return "", "", err
 	}
 
-	return fmt.Sprintf(authorizationHeaderFormat, s.keyIdentifier,
-		authSignature.SignatureType(), headerName, authSignature.String()), nil
+	return authSignature.String(), authSignature.SignatureType(), nil
 }
 
-func (s *SSHAgentSigner) SignRaw(toSign string) (string, string, error) {
-	signature, err := s.agent.Sign(s.key, []byte(toSign))
+// signWithAgent asks the connected agent to sign data and wraps the result
+// in the httpAuthSignature matching the signature format the agent actually
+// used. For RSA keys it prefers the RSA-SHA2 formats Triton/Manta advertise
+// in the HTTP Signature Auth "algorithm" parameter, falling back to the
+// legacy ssh-rsa (SHA-1) signature for agents that don't implement
+// agent.ExtendedAgent.
+func (s *SSHAgentSigner) signWithAgent(data []byte) (httpAuthSignature, error) {
+	signature, err := s.signRSAWithPreferredFlags(data)
 	if err != nil {
-		return "", "", errwrap.Wrapf("Error signing string: {{err}}", err)
+		return nil, errwrap.Wrapf("Error signing data: {{err}}", err)
 	}
 
 	keyFormat, err := keyFormatToKeyType(signature.Format)
 	if err != nil {
-		return "", "", errwrap.Wrapf("Error reading signature: {{err}}", err)
+		return nil, errwrap.Wrapf("Error reading signature: {{err}}", err)
 	}
 
-	var authSignature httpAuthSignature
 	switch keyFormat {
 	case "rsa":
-		authSignature, err = newRSASignature(signature.Blob)
-		if err != nil {
-			return "", "", errwrap.Wrapf("Error reading signature: {{err}}", err)
-		}
+		return newRSASignature(signature.Blob)
+	case "rsa-sha256":
+		return newRSASignatureWithAlgorithm(signature.Blob, "rsa-sha256")
+	case "rsa-sha512":
+		return newRSASignatureWithAlgorithm(signature.Blob, "rsa-sha512")
 	case "ecdsa":
-		authSignature, err = newECDSASignature(signature.Blob)
-		if err != nil {
-			return "", "", errwrap.Wrapf("Error reading signature: {{err}}", err)
-		}
+		return newECDSASignature(signature.Blob)
+	case "ed25519":
+		return newED25519Signature(signature.Blob)
 	default:
-		return "", "", fmt.Errorf("Unsupported algorithm from SSH agent: %s", signature.Format)
+		return nil, fmt.Errorf("Unsupported algorithm from SSH agent: %s", signature.Format)
 	}
+}
 
-	return authSignature.String(), authSignature.SignatureType(), nil
+// signRSAWithPreferredFlags signs data with s.key, requesting RSA-SHA2-256
+// (then RSA-SHA2-512) via agent.ExtendedAgent.SignWithFlags when the
+// connected agent supports it and s.key is an RSA key. It falls back to a
+// plain agent.Agent.Sign for every other case.
+func (s *SSHAgentSigner) signRSAWithPreferredFlags(data []byte) (*ssh.Signature, error) {
+	if s.extAgent != nil && s.key.Type() == ssh.KeyAlgoRSA {
+		signature, err := s.extAgent.SignWithFlags(s.key, data, agent.SignatureFlagRsaSha256)
+		if err == nil {
+			return signature, nil
+		}
+
+		signature, err = s.extAgent.SignWithFlags(s.key, data, agent.SignatureFlagRsaSha512)
+		if err == nil {
+			return signature, nil
+		}
+	}
+
+	return s.agent.Sign(s.key, data)
 }
 
 func (s *SSHAgentSigner) KeyFingerprint() string {
@@ -168,3 +188,35 @@ func (s *SSHAgentSigner) KeyFingerprint() string {
 func (s *SSHAgentSigner) DefaultAlgorithm() string {
 	return s.algorithm
 }
+
+// CallExtension invokes a named extension on the connected agent, as
+// described in section 4.7 of the ssh-agent protocol draft. This is how
+// integrations like gpg-agent's ssh-agent bridge, CA-backed SSO agents that
+// mint short-lived certificates on demand, and yubikey-agent's
+// attestation/query extensions are reached - none of them fit the plain
+// agent.Agent interface. It returns agent.ErrExtensionUnsupported (wrapped)
+// if the connected agent doesn't implement agent.ExtendedAgent, or doesn't
+// support extType.
+func (s *SSHAgentSigner) CallExtension(extType string, contents []byte) ([]byte, error) {
+	if s.extAgent == nil {
+		return nil, errwrap.Wrapf("Error calling extension: {{err}}", agent.ErrExtensionUnsupported)
+	}
+
+	response, err := s.extAgent.Extension(extType, contents)
+	if err != nil {
+		return nil, errwrap.Wrapf("Error calling extension: {{err}}", err)
+	}
+
+	return response, nil
+}
+
+// HasExtension probes whether the connected agent supports extType by
+// attempting a zero-byte extension call and inspecting the resulting error.
+func (s *SSHAgentSigner) HasExtension(extType string) bool {
+	if s.extAgent == nil {
+		return false
+	}
+
+	_, err := s.extAgent.Extension(extType, nil)
+	return err == nil || !errors.Is(err, agent.ErrExtensionUnsupported)
+}
@@ -0,0 +1,299 @@
+package authentication
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestMatchKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaPub, err := ssh.NewPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519Pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519SSHPub, err := ssh.NewPublicKey(ed25519Pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  ssh.PublicKey
+	}{
+		{"rsa", rsaPub},
+		{"ecdsa", ecdsaPub},
+		{"ed25519", ed25519SSHPub},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprint := formatPublicKeyFingerprint(tt.key, true)
+
+			signer := &SSHAgentSigner{
+				keyFingerprint: fingerprint,
+				agent:          &fakeAgent{keys: []ssh.PublicKey{tt.key}},
+			}
+
+			matched, err := signer.MatchKey()
+			if err != nil {
+				t.Fatalf("MatchKey() returned error: %s", err)
+			}
+			if string(matched.Marshal()) != string(tt.key.Marshal()) {
+				t.Fatalf("MatchKey() returned the wrong key for %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestSignWithAgentKeyFormatDispatch(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		signatureFormat string
+		wantAlgorithm   string
+	}{
+		{ssh.KeyAlgoRSA, "rsa-sha1"},
+		{"rsa-sha2-256", "rsa-sha256"},
+		{"rsa-sha2-512", "rsa-sha512"},
+		{ssh.KeyAlgoECDSA256, "ecdsa-sha256"},
+		{ssh.KeyAlgoSKECDSA256, "ecdsa-sha256"},
+		{ssh.KeyAlgoED25519, "ed25519"},
+		{ssh.KeyAlgoSKED25519, "ed25519"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.signatureFormat, func(t *testing.T) {
+			signer := &SSHAgentSigner{
+				key: rsaPub,
+				agent: &fakeAgent{
+					signFormat: tt.signatureFormat,
+					signBlob:   []byte("signature-bytes"),
+				},
+			}
+
+			authSignature, err := signer.signWithAgent([]byte("data to sign"))
+			if err != nil {
+				t.Fatalf("signWithAgent() returned error: %s", err)
+			}
+			if got := authSignature.SignatureType(); got != tt.wantAlgorithm {
+				t.Fatalf("SignatureType() = %q, want %q", got, tt.wantAlgorithm)
+			}
+		})
+	}
+}
+
+func TestSignRSAWithPreferredFlagsFallback(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPub, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("prefers rsa-sha2-256", func(t *testing.T) {
+		ext := &fakeExtendedAgent{fakeAgent: &fakeAgent{}}
+		signer := &SSHAgentSigner{key: rsaPub, agent: ext, extAgent: ext}
+
+		signature, err := signer.signRSAWithPreferredFlags([]byte("data"))
+		if err != nil {
+			t.Fatalf("signRSAWithPreferredFlags() returned error: %s", err)
+		}
+		if signature.Format != "rsa-sha2-256" {
+			t.Fatalf("Format = %q, want %q", signature.Format, "rsa-sha2-256")
+		}
+	})
+
+	t.Run("falls back to rsa-sha2-512 when 256 is rejected", func(t *testing.T) {
+		ext := &fakeExtendedAgent{fakeAgent: &fakeAgent{}, failSha256: true}
+		signer := &SSHAgentSigner{key: rsaPub, agent: ext, extAgent: ext}
+
+		signature, err := signer.signRSAWithPreferredFlags([]byte("data"))
+		if err != nil {
+			t.Fatalf("signRSAWithPreferredFlags() returned error: %s", err)
+		}
+		if signature.Format != "rsa-sha2-512" {
+			t.Fatalf("Format = %q, want %q", signature.Format, "rsa-sha2-512")
+		}
+	})
+
+	t.Run("falls back to plain Sign when both flags are rejected", func(t *testing.T) {
+		ext := &fakeExtendedAgent{
+			fakeAgent:  &fakeAgent{signFormat: ssh.KeyAlgoRSA, signBlob: []byte("legacy-sig")},
+			failSha256: true,
+			failSha512: true,
+		}
+		signer := &SSHAgentSigner{key: rsaPub, agent: ext, extAgent: ext}
+
+		signature, err := signer.signRSAWithPreferredFlags([]byte("data"))
+		if err != nil {
+			t.Fatalf("signRSAWithPreferredFlags() returned error: %s", err)
+		}
+		if signature.Format != ssh.KeyAlgoRSA {
+			t.Fatalf("Format = %q, want %q", signature.Format, ssh.KeyAlgoRSA)
+		}
+	})
+
+	t.Run("non-extended agent goes straight to plain Sign", func(t *testing.T) {
+		signer := &SSHAgentSigner{
+			key:   rsaPub,
+			agent: &fakeAgent{signFormat: ssh.KeyAlgoRSA, signBlob: []byte("legacy-sig")},
+		}
+
+		signature, err := signer.signRSAWithPreferredFlags([]byte("data"))
+		if err != nil {
+			t.Fatalf("signRSAWithPreferredFlags() returned error: %s", err)
+		}
+		if signature.Format != ssh.KeyAlgoRSA {
+			t.Fatalf("Format = %q, want %q", signature.Format, ssh.KeyAlgoRSA)
+		}
+	})
+}
+
+func TestCallExtensionAndHasExtension(t *testing.T) {
+	t.Run("no extended agent", func(t *testing.T) {
+		signer := &SSHAgentSigner{agent: &fakeAgent{}}
+
+		if signer.HasExtension("query") {
+			t.Fatal("HasExtension() = true, want false for a non-extended agent")
+		}
+		if _, err := signer.CallExtension("query", nil); err == nil {
+			t.Fatal("CallExtension() should have returned an error for a non-extended agent")
+		}
+	})
+
+	t.Run("supported extension", func(t *testing.T) {
+		ext := &fakeExtendedAgent{fakeAgent: &fakeAgent{}, response: []byte("pong")}
+		signer := &SSHAgentSigner{agent: ext, extAgent: ext}
+
+		if !signer.HasExtension("query") {
+			t.Fatal("HasExtension() = false, want true for a supported extension")
+		}
+
+		response, err := signer.CallExtension("query", []byte("ping"))
+		if err != nil {
+			t.Fatalf("CallExtension() returned error: %s", err)
+		}
+		if string(response) != "pong" {
+			t.Fatalf("CallExtension() = %q, want %q", response, "pong")
+		}
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		ext := &fakeExtendedAgent{fakeAgent: &fakeAgent{}, unsupported: true}
+		signer := &SSHAgentSigner{agent: ext, extAgent: ext}
+
+		if signer.HasExtension("query") {
+			t.Fatal("HasExtension() = true, want false for an unsupported extension")
+		}
+		if _, err := signer.CallExtension("query", nil); !errors.Is(err, agent.ErrExtensionUnsupported) {
+			t.Fatalf("CallExtension() error = %v, want wrapped agent.ErrExtensionUnsupported", err)
+		}
+	})
+}
+
+// fakeExtendedAgent extends fakeAgent with agent.ExtendedAgent's
+// SignWithFlags/Extension, to exercise the RSA-SHA2 fallback chain and
+// CallExtension/HasExtension.
+type fakeExtendedAgent struct {
+	*fakeAgent
+
+	response    []byte
+	unsupported bool
+
+	failSha256 bool
+	failSha512 bool
+}
+
+func (f *fakeExtendedAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	switch {
+	case flags&agent.SignatureFlagRsaSha256 != 0:
+		if f.failSha256 {
+			return nil, errNotImplemented
+		}
+		return &ssh.Signature{Format: "rsa-sha2-256", Blob: []byte("sha256-sig")}, nil
+	case flags&agent.SignatureFlagRsaSha512 != 0:
+		if f.failSha512 {
+			return nil, errNotImplemented
+		}
+		return &ssh.Signature{Format: "rsa-sha2-512", Blob: []byte("sha512-sig")}, nil
+	default:
+		return nil, errNotImplemented
+	}
+}
+
+func (f *fakeExtendedAgent) Extension(extType string, contents []byte) ([]byte, error) {
+	if f.unsupported {
+		return nil, agent.ErrExtensionUnsupported
+	}
+	return f.response, nil
+}
+
+// fakeAgent implements just enough of agent.Agent to exercise MatchKey and
+// signWithAgent.
+type fakeAgent struct {
+	keys []ssh.PublicKey
+
+	signFormat string
+	signBlob   []byte
+}
+
+func (f *fakeAgent) List() ([]*agent.Key, error) {
+	out := make([]*agent.Key, len(f.keys))
+	for i, k := range f.keys {
+		out[i] = &agent.Key{
+			Format: k.Type(),
+			Blob:   k.Marshal(),
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	if f.signFormat == "" {
+		return nil, errNotImplemented
+	}
+	return &ssh.Signature{Format: f.signFormat, Blob: f.signBlob}, nil
+}
+
+func (f *fakeAgent) Add(key agent.AddedKey) error            { return errNotImplemented }
+func (f *fakeAgent) Remove(key ssh.PublicKey) error           { return errNotImplemented }
+func (f *fakeAgent) RemoveAll() error                         { return errNotImplemented }
+func (f *fakeAgent) Lock(passphrase []byte) error             { return errNotImplemented }
+func (f *fakeAgent) Unlock(passphrase []byte) error           { return errNotImplemented }
+func (f *fakeAgent) Signers() ([]ssh.Signer, error)           { return nil, errNotImplemented }
+
+var errNotImplemented = errors.New("not implemented")